@@ -0,0 +1,235 @@
+// Package coinflip implements the commit-reveal joint randomness protocol
+// that a comment inside pedersen.NewVSS calls for but NewVSS does not run: a way
+// for a group of participants to jointly produce a random value that no
+// single participant, or coalition formed after seeing other participants'
+// contributions, can bias or predict. See [GJKR 99] section 4.2.
+//
+//	[GJKR 99]: Gennaro R., Jarecki S., Krawczyk H., Rabin T. (1999) Secure
+//	    Distributed Key Generation for Discrete-Log Based Cryptosystems. In:
+//	    Stern J. (eds) Advances in Cryptology — EUROCRYPT ’99. EUROCRYPT 1999.
+//	    Lecture Notes in Computer Science, vol 1592. Springer, Berlin, Heidelberg
+//	    http://groups.csail.mit.edu/cis/pubs/stasio/vss.ps.gz
+package coinflip
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+// Result is the outcome of a successful run of the joint randomness
+// protocol: the combined random value, and the members who failed to reveal
+// a value matching their earlier commitment and were disqualified.
+type Result struct {
+	Value        *big.Int
+	Disqualified []MemberID
+}
+
+// Run executes the commit-reveal joint randomness protocol among memberIDs,
+// communicating over broadcastChannel. thisMember picks r in (0, p), commits
+// to it with a salted SHA-256 hash, waits up to commitTimeout for every other
+// member's commitment, reveals (r, salt), and combines every valid reveal
+// received within revealTimeout into r = product(r_i) mod p. A member whose
+// reveal never arrives, or whose reveal doesn't match its commitment, is
+// excluded from the product and reported as disqualified.
+func Run(
+	ctx context.Context,
+	thisMember MemberID,
+	memberIDs []MemberID,
+	p *big.Int,
+	broadcastChannel net.BroadcastChannel,
+	commitTimeout, revealTimeout time.Duration,
+) (*Result, error) {
+	r, salt, commitment, err := generateCommitment(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate commitment [%v]", err)
+	}
+
+	if err := broadcastChannel.Send(&commitmentMessage{
+		SenderID:   thisMember,
+		Commitment: commitment,
+	}); err != nil {
+		return nil, fmt.Errorf("could not send commitment [%v]", err)
+	}
+
+	commitments, err := collectCommitments(
+		ctx, broadcastChannel, memberIDs, commitTimeout, thisMember, commitment,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := broadcastChannel.Send(&revealMessage{
+		SenderID: thisMember,
+		Value:    r.Bytes(),
+		Salt:     salt,
+	}); err != nil {
+		return nil, fmt.Errorf("could not send reveal [%v]", err)
+	}
+
+	revealed, disqualified, err := collectReveals(
+		ctx, broadcastChannel, commitments, revealTimeout, p, thisMember, r,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	combined := big.NewInt(1)
+	for _, value := range revealed {
+		combined.Mul(combined, value)
+		combined.Mod(combined, p)
+	}
+
+	return &Result{Value: combined, Disqualified: disqualified}, nil
+}
+
+func collectCommitments(
+	ctx context.Context,
+	broadcastChannel net.BroadcastChannel,
+	memberIDs []MemberID,
+	timeout time.Duration,
+	thisMember MemberID,
+	thisCommitment []byte,
+) (map[MemberID][]byte, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Seeded with thisMember's own commitment directly, rather than relying
+	// on broadcastChannel to echo our own Send back to us: not every pubsub
+	// implementation loops a sender's own publish back to its own handler,
+	// and if it doesn't, every run would fall one commitment short forever.
+	commitments := map[MemberID][]byte{thisMember: thisCommitment}
+	err := broadcastChannel.Recv(timeoutCtx, func(message net.Message) {
+		commitment, ok := message.Payload().(*commitmentMessage)
+		if !ok {
+			return
+		}
+		commitments[commitment.SenderID] = commitment.Commitment
+	})
+	if err != nil && err != context.DeadlineExceeded {
+		return nil, fmt.Errorf("could not collect commitments [%v]", err)
+	}
+
+	if len(commitments) < len(memberIDs) {
+		return nil, fmt.Errorf(
+			"commit phase timed out with %d of %d commitments received",
+			len(commitments),
+			len(memberIDs),
+		)
+	}
+
+	return commitments, nil
+}
+
+func collectReveals(
+	ctx context.Context,
+	broadcastChannel net.BroadcastChannel,
+	commitments map[MemberID][]byte,
+	timeout time.Duration,
+	p *big.Int,
+	thisMember MemberID,
+	thisValue *big.Int,
+) (map[MemberID]*big.Int, []MemberID, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Seeded with thisMember's own reveal directly, for the same reason
+	// collectCommitments seeds its own map: we can't assume broadcastChannel
+	// echoes our own Send back to us.
+	revealed := map[MemberID]*big.Int{thisMember: thisValue}
+	err := broadcastChannel.Recv(timeoutCtx, func(message net.Message) {
+		reveal, ok := message.Payload().(*revealMessage)
+		if !ok {
+			return
+		}
+
+		commitment, isCommitted := commitments[reveal.SenderID]
+		if !isCommitted {
+			return
+		}
+
+		if !verifyCommitment(commitment, reveal.Value, reveal.Salt) {
+			return
+		}
+
+		// A revealed value of 0 (or >= p) would zero out, or otherwise
+		// corrupt, the product every other member computes, breaking the
+		// hiding property of every Pedersen commitment built on the
+		// resulting h - even though it matches an honestly-generated
+		// commitment to 0. Reject it exactly as if the reveal never arrived.
+		value := new(big.Int).SetBytes(reveal.Value)
+		if !isInRevealRange(value, p) {
+			return
+		}
+
+		revealed[reveal.SenderID] = value
+	})
+	if err != nil && err != context.DeadlineExceeded {
+		return nil, nil, fmt.Errorf("could not collect reveals [%v]", err)
+	}
+
+	var disqualified []MemberID
+	for senderID := range commitments {
+		if _, ok := revealed[senderID]; !ok {
+			disqualified = append(disqualified, senderID)
+		}
+	}
+
+	return revealed, disqualified, nil
+}
+
+func generateCommitment(p *big.Int) (r *big.Int, salt, commitment []byte, err error) {
+	r, err = randomFromZStarP(p)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not generate r [%v]", err)
+	}
+
+	salt = make([]byte, 32)
+	if _, err := cryptorand.Read(salt); err != nil {
+		return nil, nil, nil, fmt.Errorf("could not generate salt [%v]", err)
+	}
+
+	return r, salt, commitmentFor(r.Bytes(), salt), nil
+}
+
+func commitmentFor(value, salt []byte) []byte {
+	hash := sha256.New()
+	hash.Write(value)
+	hash.Write(salt)
+	return hash.Sum(nil)
+}
+
+func verifyCommitment(commitment, value, salt []byte) bool {
+	return subtle.ConstantTimeCompare(commitment, commitmentFor(value, salt)) == 1
+}
+
+// isInRevealRange reports whether value is a valid reveal for modulus p: in
+// [1, p-1]. A reveal matching its commitment is necessary but not
+// sufficient - a member could have honestly committed to 0 or to a value
+// >= p, either of which would corrupt the product every other member
+// computes in Run.
+func isInRevealRange(value, p *big.Int) bool {
+	return value.Sign() > 0 && value.Cmp(p) < 0
+}
+
+// randomFromZStarP generates a random big.Int in [1, p).
+func randomFromZStarP(p *big.Int) (*big.Int, error) {
+	max := new(big.Int).Sub(p, big.NewInt(1))
+
+	x := big.NewInt(0)
+	for x.Sign() == 0 {
+		random, err := cryptorand.Int(cryptorand.Reader, max)
+		if err != nil {
+			return nil, err
+		}
+		x = random.Add(random, big.NewInt(1))
+	}
+
+	return x, nil
+}
@@ -0,0 +1,90 @@
+package coinflip
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestGenerateCommitmentRoundTrip(t *testing.T) {
+	p := big.NewInt(23)
+
+	r, salt, commitment, err := generateCommitment(p)
+	if err != nil {
+		t.Fatalf("could not generate commitment: %v", err)
+	}
+
+	if !verifyCommitment(commitment, r.Bytes(), salt) {
+		t.Fatal("expected a freshly generated commitment to verify against its own reveal")
+	}
+}
+
+func TestVerifyCommitmentRejectsWrongValue(t *testing.T) {
+	p := big.NewInt(23)
+
+	_, salt, commitment, err := generateCommitment(p)
+	if err != nil {
+		t.Fatalf("could not generate commitment: %v", err)
+	}
+
+	if verifyCommitment(commitment, big.NewInt(999).Bytes(), salt) {
+		t.Fatal("expected commitment verification to fail against a mismatched value")
+	}
+}
+
+func TestVerifyCommitmentRejectsWrongSalt(t *testing.T) {
+	p := big.NewInt(23)
+
+	r, salt, commitment, err := generateCommitment(p)
+	if err != nil {
+		t.Fatalf("could not generate commitment: %v", err)
+	}
+
+	wrongSalt := append([]byte{}, salt...)
+	wrongSalt[0] ^= 0xFF
+
+	if verifyCommitment(commitment, r.Bytes(), wrongSalt) {
+		t.Fatal("expected commitment verification to fail against a mismatched salt")
+	}
+}
+
+func TestRandomFromZStarPIsInRange(t *testing.T) {
+	p := big.NewInt(23)
+
+	for i := 0; i < 50; i++ {
+		x, err := randomFromZStarP(p)
+		if err != nil {
+			t.Fatalf("could not generate random value: %v", err)
+		}
+		if x.Sign() <= 0 || x.Cmp(p) >= 0 {
+			t.Fatalf("expected value in [1, p), got %v", x)
+		}
+	}
+}
+
+func TestIsInRevealRange(t *testing.T) {
+	p := big.NewInt(23)
+
+	tests := map[string]struct {
+		value *big.Int
+		valid bool
+	}{
+		"smallest valid value": {big.NewInt(1), true},
+		"largest valid value":  {big.NewInt(22), true},
+		"mid-range value":      {big.NewInt(11), true},
+		"zero":                 {big.NewInt(0), false},
+		"negative":             {big.NewInt(-1), false},
+		"equal to p":           {big.NewInt(23), false},
+		"greater than p":       {big.NewInt(24), false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := isInRevealRange(test.value, p); got != test.valid {
+				t.Errorf(
+					"isInRevealRange(%v, %v) = %v, want %v",
+					test.value, p, got, test.valid,
+				)
+			}
+		})
+	}
+}
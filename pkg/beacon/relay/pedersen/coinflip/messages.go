@@ -0,0 +1,71 @@
+package coinflip
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// MemberID identifies a single participant in the joint randomness protocol.
+type MemberID uint32
+
+// commitmentMessage is broadcast by every member during the commit phase. It
+// binds the sender to a value without revealing it.
+type commitmentMessage struct {
+	SenderID   MemberID
+	Commitment []byte
+}
+
+// Type identifies this message on the wire, for dispatch by the broadcast
+// channel.
+func (m *commitmentMessage) Type() string {
+	return "pedersen/coinflip_commitment"
+}
+
+// Marshal converts this commitmentMessage to a byte array.
+func (m *commitmentMessage) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(m); err != nil {
+		return nil, fmt.Errorf("could not marshal commitment message [%v]", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal converts a byte array to this commitmentMessage.
+func (m *commitmentMessage) Unmarshal(wire []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(wire)).Decode(m); err != nil {
+		return fmt.Errorf("could not unmarshal commitment message [%v]", err)
+	}
+	return nil
+}
+
+// revealMessage is broadcast by every member during the reveal phase, once
+// all commitments have been received. It opens the commitment made earlier.
+type revealMessage struct {
+	SenderID MemberID
+	Value    []byte
+	Salt     []byte
+}
+
+// Type identifies this message on the wire, for dispatch by the broadcast
+// channel.
+func (m *revealMessage) Type() string {
+	return "pedersen/coinflip_reveal"
+}
+
+// Marshal converts this revealMessage to a byte array.
+func (m *revealMessage) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(m); err != nil {
+		return nil, fmt.Errorf("could not marshal reveal message [%v]", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal converts a byte array to this revealMessage.
+func (m *revealMessage) Unmarshal(wire []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(wire)).Decode(m); err != nil {
+		return fmt.Errorf("could not unmarshal reveal message [%v]", err)
+	}
+	return nil
+}
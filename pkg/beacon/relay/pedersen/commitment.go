@@ -13,6 +13,14 @@
 // To stop an adversary Committer from changing the value them already committed
 // to, the scheme requires that `log_g(h)` is unknown to the Committer.
 //
+// Dealer, VerifyShare, and Reconstruct implement the full (t, n) VSS from
+// section 3 of [GJKR 99]: a dealer splits a secret into shares using two
+// random degree-t polynomials and publishes commitments to their
+// coefficients; each participant checks its share against those commitments
+// with VerifyShare, raising a Complaint and forcing a Justification from the
+// dealer if it doesn't hold; and any t+1 valid shares reconstruct the secret
+// with Reconstruct.
+//
 // You may consult our documentation for more details:
 // docs/cryptography/trapdoor-commitments.html#_pedersen_commitment
 //
@@ -90,12 +98,15 @@ func NewVSS(p, q *big.Int) (*VSS, error) {
 	}
 	g := new(big.Int).Exp(randomG, big.NewInt(2), nil) // (randomZ(0, 2^p - 1]) ^2
 
-	// Generate `h` jointly by the players as described in section 4.2 of [GJKR 99]
-	// First players have to jointly generate a random value r ∈ Z*_p with coin
-	// flipping protocol.
+	// `h` should be generated jointly by the players, with the coin-flipping
+	// protocol implemented in the coinflip package, as described in section
+	// 4.2 of [GJKR 99]. NewVSS has no access to the other players, so it
+	// falls back to a locally-generated value; callers that can reach the
+	// other players over a net.BroadcastChannel should use NewVSSJoint
+	// instead, which runs that protocol for real.
 	// To generate a random element `h` in a subgroup generated by `g` one needs
 	// to calculate `h = r^k mod p` where `k = (p - 1) / q`
-	randomValue, err := randomFromZn(p) // TODO this should be generated with coin flipping protocol
+	randomValue, err := randomFromZn(p)
 	if err != nil {
 		return nil, fmt.Errorf("randomValue generation failed [%s]", err)
 	}
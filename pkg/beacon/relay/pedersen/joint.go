@@ -0,0 +1,81 @@
+package pedersen
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/beacon/relay/pedersen/coinflip"
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+// defaultCommitTimeout and defaultRevealTimeout bound how long NewVSSJoint
+// waits, in each phase of the coinflip protocol, for the other participants
+// before giving up on stragglers.
+const (
+	defaultCommitTimeout = 2 * time.Minute
+	defaultRevealTimeout = 2 * time.Minute
+)
+
+// NewVSSJoint generates parameters for a scheme execution the same way
+// NewVSS does, except that `h` is generated jointly by the players named in
+// memberIDs, communicating over broadcastChannel, using the commit-reveal
+// joint randomness protocol from [GJKR 99] section 4.2, instead of being
+// picked locally. This satisfies the interactive assumption NewVSS's `h`
+// generation silently violates: none of the players, including thisMember,
+// can influence `h` once every other player has committed to their
+// contribution.
+//
+// Members who fail to reveal are disqualified per section 4.2 rather than
+// aborting the run for everyone else: `h` is derived from the combination of
+// whoever did reveal, and the disqualified member IDs are returned alongside
+// it so the caller can exclude them from the rest of the protocol.
+func NewVSSJoint(
+	ctx context.Context,
+	p, q *big.Int,
+	thisMember coinflip.MemberID,
+	memberIDs []coinflip.MemberID,
+	broadcastChannel net.BroadcastChannel,
+) (*VSS, []coinflip.MemberID, error) {
+	if !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		return nil, nil, fmt.Errorf("p and q have to be primes")
+	}
+
+	modulus := new(big.Int).Mod(
+		new(big.Int).Sub(p, big.NewInt(1)),
+		new(big.Int).Exp(q, big.NewInt(2), nil),
+	)
+	if modulus.Cmp(big.NewInt(0)) == 0 {
+		return nil, nil, fmt.Errorf("incorrect p and q values")
+	}
+
+	randomG, err := randomFromZn(p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("g generation failed [%s]", err)
+	}
+	g := new(big.Int).Exp(randomG, big.NewInt(2), nil)
+
+	result, err := coinflip.Run(
+		ctx,
+		thisMember,
+		memberIDs,
+		p,
+		broadcastChannel,
+		defaultCommitTimeout,
+		defaultRevealTimeout,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("joint randomness generation failed [%s]", err)
+	}
+
+	// To generate a random element `h` in a subgroup generated by `g` one
+	// needs to calculate `h = r^k mod p` where `k = (p - 1) / q`.
+	k := new(big.Int).Div(
+		new(big.Int).Sub(p, big.NewInt(1)),
+		q,
+	)
+	h := new(big.Int).Exp(result.Value, k, p)
+
+	return &VSS{p: p, q: q, g: g, h: h}, result.Disqualified, nil
+}
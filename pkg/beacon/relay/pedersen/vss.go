@@ -0,0 +1,256 @@
+package pedersen
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// Dealer samples the two degree-`threshold` polynomials behind a (t, n)
+// Pedersen VSS, publishes commitments to their coefficients, and hands each
+// participant its share of the secret. It implements the sharing half of
+// [Ped91b]; CommitmentTo/Verify on *VSS implement the single-value
+// commitment half.
+type Dealer struct {
+	vss *VSS
+
+	// coefficients and coefficientsPrime are the coefficients of f(x) and
+	// f'(x) respectively, indexed from 0 (the constant term) to threshold.
+	// f(0) = secret; f'(0) is random and only ever used to blind f's
+	// coefficients in the published commitments.
+	coefficients      []*big.Int
+	coefficientsPrime []*big.Int
+
+	// commitments[k] = g^coefficients[k] * h^coefficientsPrime[k] mod p, for
+	// k = 0..threshold. Every participant can check its share against these
+	// without learning the polynomials themselves.
+	commitments []*big.Int
+}
+
+// NewDealer samples f(x) and f'(x), two random degree-threshold polynomials
+// over Z_q with f(0) = secret, and publishes Pedersen commitments to their
+// coefficients. threshold+1 shares are required to reconstruct secret.
+func NewDealer(vss *VSS, secret *big.Int, threshold int) (*Dealer, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold must be non-negative")
+	}
+
+	coefficients, err := randomPolynomial(secret, threshold, vss.q)
+	if err != nil {
+		return nil, fmt.Errorf("could not sample f(x) [%s]", err)
+	}
+
+	coefficientsPrime, err := randomPolynomial(nil, threshold, vss.q)
+	if err != nil {
+		return nil, fmt.Errorf("could not sample f'(x) [%s]", err)
+	}
+
+	commitments := make([]*big.Int, threshold+1)
+	for k := 0; k <= threshold; k++ {
+		commitments[k] = CalculateCommitment(
+			vss, coefficients[k], coefficientsPrime[k],
+		)
+	}
+
+	return &Dealer{
+		vss:               vss,
+		coefficients:      coefficients,
+		coefficientsPrime: coefficientsPrime,
+		commitments:       commitments,
+	}, nil
+}
+
+// Commitments returns the public commitments to f(x) and f'(x)'s
+// coefficients, to be broadcast to every participant.
+func (d *Dealer) Commitments() []*big.Int {
+	return d.commitments
+}
+
+// SharesFor evaluates f and f' at index and returns the resulting share,
+// (f(index), f'(index)), to be sent privately to the participant at index.
+// index must be in [1, n]; index 0 is reserved for the secret itself, and is
+// rejected here rather than silently handed out as a share.
+func (d *Dealer) SharesFor(index int) (*Share, error) {
+	if index < 1 {
+		return nil, fmt.Errorf("share index must be >= 1, got %d", index)
+	}
+
+	x := big.NewInt(int64(index))
+	return &Share{
+		Index:  index,
+		S:      evaluatePolynomial(d.coefficients, x, d.vss.q),
+		SPrime: evaluatePolynomial(d.coefficientsPrime, x, d.vss.q),
+	}, nil
+}
+
+// Justify reveals the share the dealer computed for complaint's index, in
+// response to a participant's Complaint. Every other participant can then
+// check the revealed share against the dealer's commitments with
+// ResolveComplaint.
+func (d *Dealer) Justify(complaint *Complaint) (*Justification, error) {
+	share, err := d.SharesFor(complaint.ComplainantIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not justify complaint [%s]", err)
+	}
+	return &Justification{Index: share.Index, S: share.S, SPrime: share.SPrime}, nil
+}
+
+// Share is one participant's share of a Dealer's secret: a point (index,
+// S=f(index)) on the secret polynomial, blinded by a matching point
+// (index, SPrime=f'(index)) on the masking polynomial.
+type Share struct {
+	Index  int
+	S      *big.Int
+	SPrime *big.Int
+}
+
+// VerifyShare checks share against the Dealer's public commitments, without
+// needing the Dealer's polynomials: it holds if and only if
+//
+//	g^S * h^SPrime ≡ ∏(k=0..threshold) commitments[k]^(index^k) (mod p)
+func VerifyShare(vss *VSS, share *Share, commitments []*big.Int) bool {
+	left := CalculateCommitment(vss, share.S, share.SPrime)
+
+	right := big.NewInt(1)
+	index := big.NewInt(int64(share.Index))
+	for k, commitment := range commitments {
+		exponent := new(big.Int).Exp(index, big.NewInt(int64(k)), nil)
+		right.Mul(right, new(big.Int).Exp(commitment, exponent, vss.p))
+		right.Mod(right, vss.p)
+	}
+
+	return left.Cmp(right) == 0
+}
+
+// Complaint is raised by a participant whose share failed VerifyShare against
+// the Dealer's public commitments, per [GJKR 99] section 3's complaint round.
+type Complaint struct {
+	ComplainantIndex int
+}
+
+// Justification is the Dealer's public response to a Complaint: the exact
+// share the complainant should have received.
+type Justification struct {
+	Index  int
+	S      *big.Int
+	SPrime *big.Int
+}
+
+// ResolveComplaint checks justification against the Dealer's public
+// commitments. It returns true if the revealed share still fails
+// verification, in which case the Dealer is disqualified: it either lied
+// about the complainant's original share, or can't produce one consistent
+// with its own commitments.
+func ResolveComplaint(vss *VSS, justification *Justification, commitments []*big.Int) bool {
+	share := &Share{
+		Index:  justification.Index,
+		S:      justification.S,
+		SPrime: justification.SPrime,
+	}
+	return !VerifyShare(vss, share, commitments)
+}
+
+// Reconstruct recovers the secret from threshold+1 or more valid shares,
+// using Lagrange interpolation to evaluate the shares' polynomial at x=0.
+// It requires at least threshold+1 shares with distinct, non-zero indices:
+// fewer shares would interpolate a different polynomial than the dealer's,
+// silently yielding the wrong secret, and a repeated index makes the
+// interpolation itself ill-defined, so both are rejected outright instead of
+// risked.
+func Reconstruct(q *big.Int, threshold int, shares []*Share) (*big.Int, error) {
+	if len(shares) < threshold+1 {
+		return nil, fmt.Errorf(
+			"need at least %d shares to reconstruct, got %d",
+			threshold+1,
+			len(shares),
+		)
+	}
+
+	seenIndices := make(map[int]bool, len(shares))
+	for _, share := range shares {
+		if share.Index == 0 {
+			return nil, fmt.Errorf("share index 0 is reserved for the secret, not a share")
+		}
+		if seenIndices[share.Index] {
+			return nil, fmt.Errorf("duplicate share index [%d]", share.Index)
+		}
+		seenIndices[share.Index] = true
+	}
+
+	secret := big.NewInt(0)
+	for _, share := range shares {
+		coefficient := lagrangeCoefficientAtZero(share.Index, shares, q)
+		term := new(big.Int).Mul(share.S, coefficient)
+		secret.Add(secret, term)
+		secret.Mod(secret, q)
+	}
+
+	return secret, nil
+}
+
+// lagrangeCoefficientAtZero computes L_index(0) = ∏(j≠index) (-x_j)/(x_index - x_j)
+// mod q, the weight share `index` contributes to the value of the shares'
+// polynomial at x=0.
+func lagrangeCoefficientAtZero(index int, shares []*Share, q *big.Int) *big.Int {
+	numerator := big.NewInt(1)
+	denominator := big.NewInt(1)
+
+	xIndex := big.NewInt(int64(index))
+
+	for _, other := range shares {
+		if other.Index == index {
+			continue
+		}
+
+		xOther := big.NewInt(int64(other.Index))
+
+		numerator.Mul(numerator, new(big.Int).Neg(xOther))
+		numerator.Mod(numerator, q)
+
+		difference := new(big.Int).Sub(xIndex, xOther)
+		difference.Mod(difference, q)
+		denominator.Mul(denominator, difference)
+		denominator.Mod(denominator, q)
+	}
+
+	denominatorInverse := new(big.Int).ModInverse(denominator, q)
+
+	coefficient := new(big.Int).Mul(numerator, denominatorInverse)
+	return coefficient.Mod(coefficient, q)
+}
+
+// randomPolynomial samples a random polynomial of degree `threshold` over
+// Z_q. If constantTerm is non-nil, it is used as the polynomial's constant
+// term instead of a random one.
+func randomPolynomial(constantTerm *big.Int, threshold int, q *big.Int) ([]*big.Int, error) {
+	coefficients := make([]*big.Int, threshold+1)
+
+	for k := 0; k <= threshold; k++ {
+		if k == 0 && constantTerm != nil {
+			coefficients[k] = new(big.Int).Mod(constantTerm, q)
+			continue
+		}
+
+		coefficient, err := crand.Int(crand.Reader, q)
+		if err != nil {
+			return nil, err
+		}
+		coefficients[k] = coefficient
+	}
+
+	return coefficients, nil
+}
+
+// evaluatePolynomial evaluates the polynomial with the given coefficients
+// (ordered from the constant term up) at x, modulo q.
+func evaluatePolynomial(coefficients []*big.Int, x, q *big.Int) *big.Int {
+	result := big.NewInt(0)
+
+	for k := len(coefficients) - 1; k >= 0; k-- {
+		result.Mul(result, x)
+		result.Add(result, coefficients[k])
+		result.Mod(result, q)
+	}
+
+	return result
+}
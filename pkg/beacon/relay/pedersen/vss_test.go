@@ -0,0 +1,186 @@
+package pedersen
+
+import (
+	"math/big"
+	"testing"
+)
+
+// testVSS returns small, valid VSS parameters: p = 23, q = 11, satisfying
+// p = 2q + 1 and q^2 ∤ (p - 1), with g != 1 so that changing a share's S
+// always changes g^S. The primes are far too small for real use, but make the
+// modular arithmetic in these tests fast and easy to reason about.
+func testVSS(t *testing.T) *VSS {
+	t.Helper()
+
+	for {
+		vss, err := NewVSS(big.NewInt(23), big.NewInt(11))
+		if err != nil {
+			t.Fatalf("could not create VSS parameters: %v", err)
+		}
+		if vss.g.Cmp(big.NewInt(1)) != 0 {
+			return vss
+		}
+	}
+}
+
+func TestDealerShareRoundTrip(t *testing.T) {
+	vss := testVSS(t)
+	secret := big.NewInt(7)
+	threshold := 2
+
+	dealer, err := NewDealer(vss, secret, threshold)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+
+	commitments := dealer.Commitments()
+
+	var shares []*Share
+	for _, index := range []int{1, 2, 3, 4, 5} {
+		share, err := dealer.SharesFor(index)
+		if err != nil {
+			t.Fatalf("could not get share for index %d: %v", index, err)
+		}
+		if !VerifyShare(vss, share, commitments) {
+			t.Fatalf("share for index %d failed verification", index)
+		}
+		shares = append(shares, share)
+	}
+
+	reconstructed, err := Reconstruct(vss.q, threshold, shares[:threshold+1])
+	if err != nil {
+		t.Fatalf("could not reconstruct secret: %v", err)
+	}
+
+	expected := new(big.Int).Mod(secret, vss.q)
+	if reconstructed.Cmp(expected) != 0 {
+		t.Errorf("expected reconstructed secret %v, got %v", expected, reconstructed)
+	}
+}
+
+func TestReconstructRequiresThresholdPlusOneShares(t *testing.T) {
+	vss := testVSS(t)
+	threshold := 2
+
+	dealer, err := NewDealer(vss, big.NewInt(7), threshold)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+
+	share1, _ := dealer.SharesFor(1)
+	share2, _ := dealer.SharesFor(2)
+
+	if _, err := Reconstruct(vss.q, threshold, []*Share{share1, share2}); err == nil {
+		t.Fatal("expected reconstruction from too few shares to fail")
+	}
+}
+
+func TestReconstructRejectsDuplicateIndices(t *testing.T) {
+	vss := testVSS(t)
+	threshold := 1
+
+	dealer, err := NewDealer(vss, big.NewInt(7), threshold)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+
+	share, _ := dealer.SharesFor(1)
+
+	if _, err := Reconstruct(vss.q, threshold, []*Share{share, share}); err == nil {
+		t.Fatal("expected reconstruction from duplicate-index shares to fail")
+	}
+}
+
+func TestReconstructRejectsZeroIndex(t *testing.T) {
+	vss := testVSS(t)
+	threshold := 1
+
+	dealer, err := NewDealer(vss, big.NewInt(7), threshold)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+
+	share, _ := dealer.SharesFor(1)
+	secretAsShare := &Share{Index: 0, S: big.NewInt(7), SPrime: big.NewInt(0)}
+
+	if _, err := Reconstruct(vss.q, threshold, []*Share{share, secretAsShare}); err == nil {
+		t.Fatal("expected reconstruction with a zero share index to fail")
+	}
+}
+
+func TestSharesForRejectsIndexZero(t *testing.T) {
+	vss := testVSS(t)
+
+	dealer, err := NewDealer(vss, big.NewInt(7), 1)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+
+	if _, err := dealer.SharesFor(0); err == nil {
+		t.Fatal("expected requesting the share at index 0 to fail")
+	}
+}
+
+func TestComplaintJustificationResolvesHonestDealer(t *testing.T) {
+	vss := testVSS(t)
+
+	dealer, err := NewDealer(vss, big.NewInt(7), 2)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+	commitments := dealer.Commitments()
+
+	share, err := dealer.SharesFor(3)
+	if err != nil {
+		t.Fatalf("could not get share: %v", err)
+	}
+
+	// Simulate a bad delivery: the share a participant actually received
+	// doesn't match what the dealer committed to.
+	tampered := &Share{
+		Index:  share.Index,
+		S:      new(big.Int).Add(share.S, big.NewInt(1)),
+		SPrime: share.SPrime,
+	}
+	if VerifyShare(vss, tampered, commitments) {
+		t.Fatal("expected tampered share to fail verification")
+	}
+
+	complaint := &Complaint{ComplainantIndex: share.Index}
+	justification, err := dealer.Justify(complaint)
+	if err != nil {
+		t.Fatalf("could not justify complaint: %v", err)
+	}
+
+	if ResolveComplaint(vss, justification, commitments) {
+		t.Fatal("expected an honest dealer's justification to resolve the complaint")
+	}
+}
+
+func TestResolveComplaintDisqualifiesLyingDealer(t *testing.T) {
+	vss := testVSS(t)
+
+	// Commitments to a secret polynomial this justification is not
+	// consistent with.
+	dealer, err := NewDealer(vss, big.NewInt(7), 1)
+	if err != nil {
+		t.Fatalf("could not create dealer: %v", err)
+	}
+	commitments := dealer.Commitments()
+
+	share, err := dealer.SharesFor(1)
+	if err != nil {
+		t.Fatalf("could not get share: %v", err)
+	}
+
+	// The revealed S is off by one from the committed f(1).
+	justification := &Justification{
+		Index:  share.Index,
+		S:      new(big.Int).Add(share.S, big.NewInt(1)),
+		SPrime: share.SPrime,
+	}
+
+	if !ResolveComplaint(vss, justification, commitments) {
+		t.Fatal("expected a justification inconsistent with the commitments to disqualify the dealer")
+	}
+}
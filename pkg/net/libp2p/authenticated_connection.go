@@ -1,6 +1,9 @@
 package libp2p
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"net"
 
@@ -12,12 +15,19 @@ import (
 	protoio "github.com/gogo/protobuf/io"
 )
 
+// connectionNonceSize is the size, in bytes, of the fresh per-connection
+// nonce the initiator generates for Act1 and both sides mix into every
+// transcript hash for the lifetime of the connection.
+const connectionNonceSize = 16
+
 // Enough space for a proto-encoded envelope with a message, peer.ID, and sig.
 const maxFrameSize = 1024
 
 // authenticatedConnection turns inbound and outbound unauthenticated,
-// plain-text connections into authenticated, plain-text connections. Noticeably,
-// it does not guarantee confidentiality as it does not encrypt connections.
+// plain-text connections into authenticated connections. By default it still
+// does not guarantee confidentiality, as it does not encrypt connections; pass
+// WithEncryption to additionally wrap the connection with the ChaCha20-Poly1305
+// session keys derived during the handshake.
 type authenticatedConnection struct {
 	net.Conn
 
@@ -26,6 +36,24 @@ type authenticatedConnection struct {
 
 	remotePeerID        peer.ID
 	remotePeerPublicKey libp2pcrypto.PubKey
+
+	encryptionEnabled bool
+	sessionKeys       *handshake.SessionKeys
+
+	blacklist *Blacklist
+
+	// connectionNonce is the fresh 128-bit nonce the initiator generates for
+	// Act1; both sides mix it into every transcript hash computed for this
+	// connection. The nonce is entirely initiator-supplied, so on its own it
+	// does not prove the Act1 envelope is fresh rather than replayed from an
+	// earlier connection; responderReceiveAct1 additionally checks it against
+	// sharedConnectionNonceCache to reject a repeat.
+	connectionNonce []byte
+	// transcript is the concatenation, in order, of every Act message this
+	// connection has exchanged so far (not counting the one currently being
+	// verified). Each Act's signature covers SHA-256(connectionNonce ||
+	// transcript || thisMessage), binding it to everything that came before.
+	transcript []byte
 }
 
 // newAuthenticatedInboundConnection is the connection that's formed by
@@ -39,12 +67,16 @@ func newAuthenticatedInboundConnection(
 	localPeerID peer.ID,
 	privateKey libp2pcrypto.PrivKey,
 	remotePeerID peer.ID,
+	options ...ConnectionOption,
 ) (*authenticatedConnection, error) {
 	ac := &authenticatedConnection{
 		Conn:                unauthenticatedConn,
 		localPeerID:         localPeerID,
 		localPeerPrivateKey: privateKey,
 	}
+	for _, option := range options {
+		option(ac)
+	}
 
 	if err := ac.runHandshakeAsResponder(); err != nil {
 		// close the conn before returning (if it hasn't already)
@@ -53,6 +85,11 @@ func newAuthenticatedInboundConnection(
 		return nil, fmt.Errorf("connection handshake failed [%v]", err)
 	}
 
+	if err := ac.upgradeToEncrypted(); err != nil {
+		ac.Close()
+		return nil, fmt.Errorf("connection handshake failed [%v]", err)
+	}
+
 	return ac, nil
 }
 
@@ -66,6 +103,7 @@ func newAuthenticatedOutboundConnection(
 	localPeerID peer.ID,
 	privateKey libp2pcrypto.PrivKey,
 	remotePeerID peer.ID,
+	options ...ConnectionOption,
 ) (*authenticatedConnection, error) {
 	remotePublicKey, err := remotePeerID.ExtractPublicKey()
 	if err != nil {
@@ -82,15 +120,46 @@ func newAuthenticatedOutboundConnection(
 		remotePeerID:        remotePeerID,
 		remotePeerPublicKey: remotePublicKey,
 	}
+	for _, option := range options {
+		option(ac)
+	}
+
+	if ac.blacklist != nil && ac.blacklist.Contains(remotePeerID) {
+		return nil, fmt.Errorf(
+			"refusing to connect to blacklisted peer [%v]",
+			remotePeerID,
+		)
+	}
 
 	if err := ac.runHandshakeAsInitiator(); err != nil {
 		ac.Close()
 		return nil, fmt.Errorf("connection handshake failed [%v]", err)
 	}
 
+	if err := ac.upgradeToEncrypted(); err != nil {
+		ac.Close()
+		return nil, fmt.Errorf("connection handshake failed [%v]", err)
+	}
+
 	return ac, nil
 }
 
+// upgradeToEncrypted wraps ac.Conn with encryptedConn when the connection was
+// built with WithEncryption. It is a no-op otherwise.
+func (ac *authenticatedConnection) upgradeToEncrypted() error {
+	if !ac.encryptionEnabled {
+		return nil
+	}
+
+	encrypted, err := newEncryptedConn(ac.Conn, ac.sessionKeys)
+	if err != nil {
+		return fmt.Errorf("could not enable encryption [%v]", err)
+	}
+
+	ac.Conn = encrypted
+	return nil
+}
+
 func (ac *authenticatedConnection) runHandshakeAsInitiator() error {
 	// initiator station
 
@@ -144,31 +213,46 @@ func (ac *authenticatedConnection) runHandshakeAsInitiator() error {
 		return err
 	}
 
+	ac.sessionKeys = initiatorAct3.SessionKeys()
+
 	return nil
 }
 
-// initiatorSendAct1 signs a marshaled *handshake.Act1Message, prepares
-// the message in a pb.HandshakeEnvelope, and sends the message to the responder
+// initiatorSendAct1 generates this connection's nonce, signs the resulting
+// transcript hash of the marshaled *handshake.Act1Message, prepares the
+// message in a pb.HandshakeEnvelope, and sends the message to the responder
 // (over the open connection) from the initiator.
 func (ac *authenticatedConnection) initiatorSendAct1(
 	act1WireMessage []byte,
 	initiatorConnectionWriter protoio.WriteCloser,
 ) error {
-	signedAct1Message, err := ac.localPeerPrivateKey.Sign(act1WireMessage)
+	nonce := make([]byte, connectionNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("could not generate connection nonce [%v]", err)
+	}
+	ac.connectionNonce = nonce
+
+	transcriptHash := ac.transcriptHash(act1WireMessage)
+
+	signedAct1Message, err := ac.localPeerPrivateKey.Sign(transcriptHash)
 	if err != nil {
 		return err
 	}
 
 	act1Envelope := &pb.HandshakeEnvelope{
-		Message:   act1WireMessage,
-		PeerID:    []byte(ac.localPeerID),
-		Signature: signedAct1Message,
+		Message:        act1WireMessage,
+		PeerID:         []byte(ac.localPeerID),
+		Signature:      signedAct1Message,
+		Nonce:          nonce,
+		TranscriptHash: transcriptHash,
 	}
 
 	if err := initiatorConnectionWriter.WriteMsg(act1Envelope); err != nil {
 		return err
 	}
 
+	ac.extendTranscript(act1WireMessage)
+
 	return nil
 }
 
@@ -189,41 +273,48 @@ func (ac *authenticatedConnection) initiatorReceiveAct2(
 	if err := ac.verify(
 		ac.remotePeerID,
 		peer.ID(act2Envelope.GetPeerID()),
-		act2Envelope.GetMessage(),
-		act2Envelope.GetSignature(),
+		&act2Envelope,
 	); err != nil {
 		return nil, err
 	}
 
 	if err := act2Message.Unmarshal(act2Envelope.Message); err != nil {
+		ac.recordHandshakeFailure(ac.remotePeerID, ReasonMalformedEnvelope)
 		return nil, err
 	}
 
 	return act2Message, nil
 }
 
-// initiatorSendAct3 signs a marshaled *handshake.Act3Message, prepares the
-// message in a pb.HandshakeEnvelope, and sends the message to the responder
-// (over the open connection) from the initiator.
+// initiatorSendAct3 signs the running transcript hash of the marshaled
+// *handshake.Act3Message, prepares the message in a pb.HandshakeEnvelope, and
+// sends the message to the responder (over the open connection) from the
+// initiator.
 func (ac *authenticatedConnection) initiatorSendAct3(
 	act3WireMessage []byte,
 	initiatorConnectionWriter protoio.WriteCloser,
 ) error {
-	signedAct3Message, err := ac.localPeerPrivateKey.Sign(act3WireMessage)
+	transcriptHash := ac.transcriptHash(act3WireMessage)
+
+	signedAct3Message, err := ac.localPeerPrivateKey.Sign(transcriptHash)
 	if err != nil {
 		return err
 	}
 
 	act3Envelope := &pb.HandshakeEnvelope{
-		Message:   act3WireMessage,
-		PeerID:    []byte(ac.localPeerID),
-		Signature: signedAct3Message,
+		Message:        act3WireMessage,
+		PeerID:         []byte(ac.localPeerID),
+		Signature:      signedAct3Message,
+		Nonce:          ac.connectionNonce,
+		TranscriptHash: transcriptHash,
 	}
 
 	if err := initiatorConnectionWriter.WriteMsg(act3Envelope); err != nil {
 		return err
 	}
 
+	ac.extendTranscript(act3WireMessage)
+
 	return nil
 }
 
@@ -274,11 +365,18 @@ func (ac *authenticatedConnection) runHandshakeAsResponder() error {
 		return err
 	}
 
+	ac.sessionKeys = responderAct3.SessionKeys()
+
 	return nil
 }
 
-// responderReceiveAct1 unmarshals a pb.HandshakeEnvelope from an initiator,
-// verifies that the signed messages matches the expected peer.ID, and returns
+// responderReceiveAct1 unmarshals a pb.HandshakeEnvelope from an initiator and
+// verifies the envelope's signature before pinning the sender's identity onto
+// the connection, so that a malformed or unverifiable Act1 can never pin an
+// attacker-chosen peer.ID. Because the connection nonce is initiator-supplied
+// and a replayed Act1 would otherwise reproduce an identical, validly-signed
+// transcript hash, it also rejects any nonce sharedConnectionNonceCache has
+// already seen before trusting the signature at all. On success, it returns
 // the handshake.Act1Message for processing by the responder.
 func (ac *authenticatedConnection) responderReceiveAct1(
 	responderConnectionReader protoio.ReadCloser,
@@ -291,52 +389,93 @@ func (ac *authenticatedConnection) responderReceiveAct1(
 		return nil, err
 	}
 
-	// Libp2p specific step: the responder has no knowledge of the initiator
-	// until after the handshake has succeeded, the connection has been
-	// upgraded, and identity information is exchanged. This provides an
-	// element of identity hiding for the initiator. To help prevent
-	// malicious interference, we want to pin this identity for the duration
-	// of the connection.
-	ac.remotePeerID = peer.ID(act1Envelope.GetPeerID())
+	// candidatePeerID has not been pinned yet and must not be trusted until
+	// the envelope's signature has been verified below.
+	candidatePeerID := peer.ID(act1Envelope.GetPeerID())
 
-	if err := ac.verify(
-		ac.remotePeerID,
-		peer.ID(act1Envelope.GetPeerID()),
-		act1Envelope.GetMessage(),
+	if ac.blacklist != nil && ac.blacklist.Contains(candidatePeerID) {
+		return nil, fmt.Errorf(
+			"refusing to complete handshake with blacklisted peer [%v]",
+			candidatePeerID,
+		)
+	}
+
+	if len(act1Envelope.GetNonce()) != connectionNonceSize {
+		ac.recordHandshakeFailure(candidatePeerID, ReasonMalformedEnvelope)
+		return nil, fmt.Errorf(
+			"act1 envelope carries no connection nonce",
+		)
+	}
+	ac.connectionNonce = act1Envelope.GetNonce()
+
+	if sharedConnectionNonceCache.Observe(ac.connectionNonce) {
+		ac.recordHandshakeFailure(candidatePeerID, ReasonHandshakeVerificationFailed)
+		return nil, fmt.Errorf(
+			"act1 connection nonce has already been seen; refusing possible replay",
+		)
+	}
+
+	expectedTranscriptHash := ac.transcriptHash(act1Envelope.GetMessage())
+	if !bytes.Equal(expectedTranscriptHash, act1Envelope.GetTranscriptHash()) {
+		ac.recordHandshakeFailure(candidatePeerID, ReasonHandshakeVerificationFailed)
+		return nil, fmt.Errorf("act1 transcript hash does not match local view")
+	}
+
+	if err := verifyEnvelope(
+		candidatePeerID,
+		expectedTranscriptHash,
 		act1Envelope.GetSignature(),
 	); err != nil {
+		ac.recordHandshakeFailure(candidatePeerID, ReasonHandshakeVerificationFailed)
 		return nil, err
 	}
 
+	// Libp2p specific step: the responder has no knowledge of the initiator
+	// until after the handshake has succeeded, the connection has been
+	// upgraded, and identity information is exchanged. This provides an
+	// element of identity hiding for the initiator. Now that the envelope's
+	// signature has verified, we want to pin this identity for the duration
+	// of the connection.
+	ac.remotePeerID = candidatePeerID
+	ac.extendTranscript(act1Envelope.GetMessage())
+
 	if err := act1Message.Unmarshal(act1Envelope.Message); err != nil {
+		ac.recordHandshakeFailure(ac.remotePeerID, ReasonMalformedEnvelope)
 		return nil, err
 	}
 
 	return act1Message, nil
 }
 
-// responderSendAct2 signs a marshaled *handshake.Act2Message, prepares the
-// message in a pb.HandshakeEnvelope, and sends the message to the initiator
-// (over the open connection) from the responder.
+// responderSendAct2 signs the running transcript hash of the marshaled
+// *handshake.Act2Message, prepares the message in a pb.HandshakeEnvelope, and
+// sends the message to the initiator (over the open connection) from the
+// responder.
 func (ac *authenticatedConnection) responderSendAct2(
 	act2WireMessage []byte,
 	responderConnectionWriter protoio.WriteCloser,
 ) error {
-	signedAct2Message, err := ac.localPeerPrivateKey.Sign(act2WireMessage)
+	transcriptHash := ac.transcriptHash(act2WireMessage)
+
+	signedAct2Message, err := ac.localPeerPrivateKey.Sign(transcriptHash)
 	if err != nil {
 		return err
 	}
 
 	act2Envelope := &pb.HandshakeEnvelope{
-		Message:   act2WireMessage,
-		PeerID:    []byte(ac.localPeerID),
-		Signature: signedAct2Message,
+		Message:        act2WireMessage,
+		PeerID:         []byte(ac.localPeerID),
+		Signature:      signedAct2Message,
+		Nonce:          ac.connectionNonce,
+		TranscriptHash: transcriptHash,
 	}
 
 	if err := responderConnectionWriter.WriteMsg(act2Envelope); err != nil {
 		return err
 	}
 
+	ac.extendTranscript(act2WireMessage)
+
 	return nil
 }
 
@@ -357,13 +496,13 @@ func (ac *authenticatedConnection) responderReceiveAct3(
 	if err := ac.verify(
 		ac.remotePeerID,
 		peer.ID(act3Envelope.GetPeerID()),
-		act3Envelope.GetMessage(),
-		act3Envelope.GetSignature(),
+		&act3Envelope,
 	); err != nil {
 		return nil, err
 	}
 
 	if err := act3Message.Unmarshal(act3Envelope.Message); err != nil {
+		ac.recordHandshakeFailure(ac.remotePeerID, ReasonMalformedEnvelope)
 		return nil, err
 	}
 
@@ -371,17 +510,76 @@ func (ac *authenticatedConnection) responderReceiveAct3(
 }
 
 // verify checks to see if the pinned (static) identity matches the message
-// sender's identity before running through the signature verification check.
+// sender's identity, that envelope's transcript hash matches ac's local view
+// of everything exchanged on this connection so far, and that envelope's
+// signature verifies over that transcript hash. It is used for Act2 and
+// Act3, where ac.remotePeerID has already been pinned; Act1 has no pinned
+// identity yet and verifies inline in responderReceiveAct1, before pinning
+// one. A failure of any check is reported to ac.blacklist, if one is
+// configured, so repeated failures from the same peer get it blacklisted.
 func (ac *authenticatedConnection) verify(
 	pinned, sender peer.ID,
-	messageBytes, signatureBytes []byte,
+	envelope *pb.HandshakeEnvelope,
 ) error {
 	if pinned != sender {
+		ac.recordHandshakeFailure(sender, ReasonHandshakeVerificationFailed)
 		return fmt.Errorf(
 			"pinned identity [%v] does not match sender identity [%v]",
 			pinned,
 			sender,
 		)
 	}
-	return verifyEnvelope(sender, messageBytes, signatureBytes)
+
+	expectedTranscriptHash := ac.transcriptHash(envelope.GetMessage())
+	if !bytes.Equal(expectedTranscriptHash, envelope.GetTranscriptHash()) {
+		ac.recordHandshakeFailure(sender, ReasonHandshakeVerificationFailed)
+		return fmt.Errorf("transcript hash does not match local view")
+	}
+
+	if err := verifyEnvelope(
+		sender,
+		expectedTranscriptHash,
+		envelope.GetSignature(),
+	); err != nil {
+		ac.recordHandshakeFailure(sender, ReasonHandshakeVerificationFailed)
+		return err
+	}
+
+	ac.extendTranscript(envelope.GetMessage())
+	return nil
+}
+
+// transcriptHash returns SHA-256(connectionNonce || transcript || message),
+// the value each Act's signature covers instead of the Act message alone.
+// Binding every signature to the full history of the connection, rather than
+// to one message in isolation, stops a captured envelope from being replayed
+// into the middle of a different, already-progressing connection: Act2 and
+// Act3 each depend on the responder's own fresh contribution to the
+// transcript. It does not by itself stop a captured Act1 from being replayed
+// onto a brand-new connection, since nothing in Act1's transcript hash has
+// been contributed by the responder yet; responderReceiveAct1 closes that
+// gap separately, by rejecting connection nonces it has already seen.
+func (ac *authenticatedConnection) transcriptHash(message []byte) []byte {
+	hash := sha256.New()
+	hash.Write(ac.connectionNonce)
+	hash.Write(ac.transcript)
+	hash.Write(message)
+	return hash.Sum(nil)
+}
+
+// extendTranscript appends message to ac's running transcript, once it has
+// been verified (or, for an outbound message, once it has been sent).
+func (ac *authenticatedConnection) extendTranscript(message []byte) {
+	ac.transcript = append(ac.transcript, message...)
+}
+
+// recordHandshakeFailure forwards a handshake failure from sender to
+// ac.blacklist, if one is configured. It is a no-op otherwise.
+func (ac *authenticatedConnection) recordHandshakeFailure(
+	sender peer.ID,
+	reason BlacklistReason,
+) {
+	if ac.blacklist != nil {
+		ac.blacklist.RecordHandshakeFailure(sender, reason)
+	}
 }
@@ -0,0 +1,227 @@
+package libp2p
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/keep-network/keep-core/pkg/net/gen/pb"
+	libp2pcrypto "github.com/libp2p/go-libp2p-crypto"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	protoio "github.com/gogo/protobuf/io"
+)
+
+func generateTestIdentity(t *testing.T) (libp2pcrypto.PrivKey, peer.ID) {
+	t.Helper()
+
+	privateKey, publicKey, err := libp2pcrypto.GenerateEd25519Key(crand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key pair: %v", err)
+	}
+
+	id, err := peer.IDFromPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("could not derive peer id: %v", err)
+	}
+
+	return privateKey, id
+}
+
+func TestHandshakeRoundTrip(t *testing.T) {
+	initiatorConn, responderConn := net.Pipe()
+	defer initiatorConn.Close()
+	defer responderConn.Close()
+
+	initiatorKey, initiatorID := generateTestIdentity(t)
+	responderKey, responderID := generateTestIdentity(t)
+
+	errs := make(chan error, 2)
+
+	go func() {
+		_, err := newAuthenticatedOutboundConnection(
+			initiatorConn, initiatorID, initiatorKey, responderID,
+		)
+		errs <- err
+	}()
+
+	go func() {
+		_, err := newAuthenticatedInboundConnection(
+			responderConn, responderID, responderKey, "",
+		)
+		errs <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+	}
+}
+
+// TestHandshakeRoundTripWithEncryption runs the real handshake with
+// WithEncryption on both ends, so the session keys both ends use are the ones
+// deriveSessionKeys derives from the ephemeral keys exchanged over the wire -
+// not the hand-paired
+// SessionKeys encrypted_connection_test.go builds for testing encryptedConn
+// in isolation - and confirms both sides can still exchange data afterward.
+func TestHandshakeRoundTripWithEncryption(t *testing.T) {
+	initiatorConn, responderConn := net.Pipe()
+	defer initiatorConn.Close()
+	defer responderConn.Close()
+
+	initiatorKey, initiatorID := generateTestIdentity(t)
+	responderKey, responderID := generateTestIdentity(t)
+
+	initiatorResult := make(chan *authenticatedConnection, 1)
+	responderResult := make(chan *authenticatedConnection, 1)
+	errs := make(chan error, 2)
+
+	go func() {
+		conn, err := newAuthenticatedOutboundConnection(
+			initiatorConn, initiatorID, initiatorKey, responderID, WithEncryption(),
+		)
+		initiatorResult <- conn
+		errs <- err
+	}()
+
+	go func() {
+		conn, err := newAuthenticatedInboundConnection(
+			responderConn, responderID, responderKey, "", WithEncryption(),
+		)
+		responderResult <- conn
+		errs <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+	}
+
+	initiator := <-initiatorResult
+	responder := <-responderResult
+
+	message := []byte("data exchanged after a real handshake derived these session keys")
+	if _, err := initiator.Write(message); err != nil {
+		t.Fatalf("could not write message: %v", err)
+	}
+
+	received := make([]byte, len(message))
+	if _, err := io.ReadFull(responder, received); err != nil {
+		t.Fatalf("could not read message: %v", err)
+	}
+
+	if !bytes.Equal(received, message) {
+		t.Errorf("expected %q, got %q", message, received)
+	}
+}
+
+func TestResponderRejectsTamperedAct1Envelope(t *testing.T) {
+	attackerConn, responderRawConn := net.Pipe()
+	defer attackerConn.Close()
+	defer responderRawConn.Close()
+
+	initiatorKey, initiatorID := generateTestIdentity(t)
+	responderKey, responderID := generateTestIdentity(t)
+
+	ac := &authenticatedConnection{
+		Conn:                responderRawConn,
+		localPeerID:         responderID,
+		localPeerPrivateKey: responderKey,
+	}
+
+	nonce := bytes.Repeat([]byte{0x01}, connectionNonceSize)
+	message := make([]byte, 32)
+	transcriptHash := (&authenticatedConnection{connectionNonce: nonce}).transcriptHash(message)
+
+	signature, err := initiatorKey.Sign(transcriptHash)
+	if err != nil {
+		t.Fatalf("could not sign message: %v", err)
+	}
+
+	envelope := &pb.HandshakeEnvelope{
+		// Message is tampered with after the signature and transcript hash
+		// were computed over the original bytes above.
+		Message:        []byte{0xFF, 0xFF, 0xFF, 0xFF},
+		PeerID:         []byte(initiatorID),
+		Signature:      signature,
+		Nonce:          nonce,
+		TranscriptHash: transcriptHash,
+	}
+
+	readDone := make(chan error, 1)
+	go func() {
+		_, err := ac.responderReceiveAct1(protoio.NewDelimitedReader(responderRawConn, maxFrameSize))
+		readDone <- err
+	}()
+
+	writer := protoio.NewDelimitedWriter(attackerConn)
+	if err := writer.WriteMsg(envelope); err != nil {
+		t.Fatalf("could not write envelope: %v", err)
+	}
+
+	if err := <-readDone; err == nil {
+		t.Fatal("expected a tampered act1 envelope to be rejected")
+	}
+}
+
+func TestResponderRejectsReplayedAct1Envelope(t *testing.T) {
+	initiatorKey, initiatorID := generateTestIdentity(t)
+	_, responderID := generateTestIdentity(t)
+
+	nonce := bytes.Repeat([]byte{0x02}, connectionNonceSize)
+	message := make([]byte, 32) // a validly-shaped, if meaningless, Act1 payload
+
+	transcriptHash := (&authenticatedConnection{connectionNonce: nonce}).transcriptHash(message)
+
+	signature, err := initiatorKey.Sign(transcriptHash)
+	if err != nil {
+		t.Fatalf("could not sign message: %v", err)
+	}
+
+	envelope := &pb.HandshakeEnvelope{
+		Message:        message,
+		PeerID:         []byte(initiatorID),
+		Signature:      signature,
+		Nonce:          nonce,
+		TranscriptHash: transcriptHash,
+	}
+
+	deliver := func() error {
+		attackerConn, responderRawConn := net.Pipe()
+		defer attackerConn.Close()
+		defer responderRawConn.Close()
+
+		key, _ := generateTestIdentity(t)
+
+		ac := &authenticatedConnection{
+			Conn:                responderRawConn,
+			localPeerID:         responderID,
+			localPeerPrivateKey: key,
+		}
+
+		readDone := make(chan error, 1)
+		go func() {
+			_, err := ac.responderReceiveAct1(protoio.NewDelimitedReader(responderRawConn, maxFrameSize))
+			readDone <- err
+		}()
+
+		writer := protoio.NewDelimitedWriter(attackerConn)
+		if err := writer.WriteMsg(envelope); err != nil {
+			t.Fatalf("could not write envelope: %v", err)
+		}
+
+		return <-readDone
+	}
+
+	if err := deliver(); err != nil {
+		t.Fatalf("expected the first delivery of a fresh nonce to succeed, got: %v", err)
+	}
+
+	if err := deliver(); err == nil {
+		t.Fatal("expected replaying the same Act1 envelope onto a new connection to be rejected")
+	}
+}
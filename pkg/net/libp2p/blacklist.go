@@ -0,0 +1,235 @@
+package libp2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// BlacklistReason records why a peer was added to a Blacklist.
+type BlacklistReason string
+
+const (
+	// ReasonHandshakeVerificationFailed marks a peer blacklisted after its
+	// handshake envelope repeatedly failed signature verification.
+	ReasonHandshakeVerificationFailed BlacklistReason = "handshake-verification-failed"
+	// ReasonMalformedEnvelope marks a peer blacklisted after repeatedly
+	// sending Act1 envelopes without a well-formed connection nonce, or Act
+	// messages that fail to unmarshal. An envelope that fails to decode at
+	// all is not counted.
+	ReasonMalformedEnvelope BlacklistReason = "malformed-handshake-envelope"
+	// ReasonManual marks a peer blacklisted through the CLI or another
+	// component, rather than automatically by handshake failures.
+	ReasonManual BlacklistReason = "manual"
+)
+
+const (
+	// failureWindow is the window, opened by a peer's first counted failure,
+	// within which handshake failures from the same peer are counted
+	// together. It does not slide: the first failure arriving after it has
+	// elapsed opens a new window with a count of one.
+	failureWindow = 10 * time.Minute
+	// failuresBeforeBlacklist is how many failures inside failureWindow it
+	// takes to automatically blacklist a peer.
+	failuresBeforeBlacklist = 3
+	// initialFailureTTL is the TTL applied the first time a peer is
+	// automatically blacklisted within a failureWindow; it doubles on every
+	// subsequent offense in that same window, and starts over with the next
+	// window.
+	initialFailureTTL = 1 * time.Minute
+	// maxFailureTTL caps how long an automatic blacklist entry can last.
+	maxFailureTTL = 24 * time.Hour
+)
+
+// BlacklistEvent is emitted whenever a peer is added to or removed from a
+// Blacklist, so other components (chain, DKG) can react - for example, by
+// dropping a peer from an in-progress protocol the moment it's blacklisted.
+type BlacklistEvent struct {
+	PeerID peer.ID
+	Reason BlacklistReason
+	Added  bool // false for a Remove event
+}
+
+// BlacklistRecord is the persisted state for a single blacklisted peer.
+type BlacklistRecord struct {
+	Reason    BlacklistReason `json:"reason"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// BlacklistStore persists Blacklist entries across restarts.
+type BlacklistStore interface {
+	Save(id peer.ID, record BlacklistRecord) error
+	Delete(id peer.ID) error
+	LoadAll() (map[peer.ID]BlacklistRecord, error)
+}
+
+type failureTracker struct {
+	count       int
+	windowStart time.Time
+}
+
+// Blacklist tracks peers whose connections are rejected before their
+// handshake envelopes are verified or answered, either because they were added manually through the CLI or because they
+// repeatedly failed handshake verification.
+type Blacklist struct {
+	store BlacklistStore
+
+	mutex    sync.Mutex
+	entries  map[peer.ID]BlacklistRecord
+	failures map[peer.ID]*failureTracker
+
+	subscribersMutex sync.Mutex
+	subscribers      []chan BlacklistEvent
+}
+
+// NewBlacklist loads existing entries from store and returns a ready-to-use
+// Blacklist backed by it.
+func NewBlacklist(store BlacklistStore) (*Blacklist, error) {
+	entries, err := store.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not load blacklist [%v]", err)
+	}
+
+	return &Blacklist{
+		store:    store,
+		entries:  entries,
+		failures: make(map[peer.ID]*failureTracker),
+	}, nil
+}
+
+// Add blacklists id for the given ttl, for the given reason, persisting the
+// entry to the backing store and notifying subscribers.
+func (b *Blacklist) Add(id peer.ID, reason BlacklistReason, ttl time.Duration) error {
+	record := BlacklistRecord{Reason: reason, ExpiresAt: time.Now().Add(ttl)}
+
+	b.mutex.Lock()
+	b.entries[id] = record
+	b.mutex.Unlock()
+
+	if err := b.store.Save(id, record); err != nil {
+		return fmt.Errorf("could not persist blacklist entry for [%v] [%v]", id, err)
+	}
+
+	b.publish(BlacklistEvent{PeerID: id, Reason: reason, Added: true})
+	return nil
+}
+
+// Remove clears any blacklist entry for id, persists the removal, and
+// notifies subscribers if an entry actually existed.
+func (b *Blacklist) Remove(id peer.ID) error {
+	b.mutex.Lock()
+	record, exists := b.entries[id]
+	delete(b.entries, id)
+	delete(b.failures, id)
+	b.mutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	if err := b.store.Delete(id); err != nil {
+		return fmt.Errorf("could not remove blacklist entry for [%v] [%v]", id, err)
+	}
+
+	b.publish(BlacklistEvent{PeerID: id, Reason: record.Reason, Added: false})
+	return nil
+}
+
+// Contains reports whether id currently has a non-expired blacklist entry.
+func (b *Blacklist) Contains(id peer.ID) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	record, exists := b.entries[id]
+	if !exists {
+		return false
+	}
+	if time.Now().After(record.ExpiresAt) {
+		delete(b.entries, id)
+		return false
+	}
+	return true
+}
+
+// RecordHandshakeFailure notes a handshake verification or unmarshal failure
+// from id. Once failuresBeforeBlacklist failures land inside the same
+// failureWindow, id is automatically blacklisted with an exponentially
+// growing TTL, doubling on every subsequent batch of offenses within that
+// window. Once the window has elapsed, both the count and the TTL start over.
+func (b *Blacklist) RecordHandshakeFailure(id peer.ID, reason BlacklistReason) {
+	now := time.Now()
+
+	b.mutex.Lock()
+	tracker, exists := b.failures[id]
+	if !exists || now.Sub(tracker.windowStart) > failureWindow {
+		tracker = &failureTracker{windowStart: now}
+		b.failures[id] = tracker
+	}
+	tracker.count++
+
+	offense := tracker.count / failuresBeforeBlacklist
+	shouldBlacklist := tracker.count%failuresBeforeBlacklist == 0
+	b.mutex.Unlock()
+
+	if !shouldBlacklist {
+		return
+	}
+
+	ttl := initialFailureTTL << uint(offense-1)
+	if ttl <= 0 || ttl > maxFailureTTL {
+		ttl = maxFailureTTL
+	}
+
+	// Best-effort: if persistence fails, the in-memory entry added by Add is
+	// still in place, so the peer is still rejected until the process
+	// restarts.
+	if err := b.Add(id, reason, ttl); err != nil {
+		fmt.Printf("could not persist automatic blacklist entry for [%v] [%v]\n", id, err)
+	}
+}
+
+// Subscribe registers handler to be invoked, on its own goroutine, for every
+// BlacklistEvent emitted from this point on. It returns a function that
+// unregisters handler.
+func (b *Blacklist) Subscribe(handler func(BlacklistEvent)) func() {
+	subscriber := make(chan BlacklistEvent, 16)
+
+	b.subscribersMutex.Lock()
+	b.subscribers = append(b.subscribers, subscriber)
+	b.subscribersMutex.Unlock()
+
+	go func() {
+		for event := range subscriber {
+			handler(event)
+		}
+	}()
+
+	return func() {
+		b.subscribersMutex.Lock()
+		defer b.subscribersMutex.Unlock()
+
+		for i, candidate := range b.subscribers {
+			if candidate == subscriber {
+				b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+				close(subscriber)
+				break
+			}
+		}
+	}
+}
+
+func (b *Blacklist) publish(event BlacklistEvent) {
+	b.subscribersMutex.Lock()
+	defer b.subscribersMutex.Unlock()
+
+	for _, subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// subscriber isn't keeping up; drop the event rather than block
+			// handshake processing on it.
+		}
+	}
+}
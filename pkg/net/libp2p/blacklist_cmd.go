@@ -0,0 +1,106 @@
+package libp2p
+
+import (
+	"fmt"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/urfave/cli"
+)
+
+// BlacklistCommand returns a CLI command for inspecting and manually managing
+// the peer Blacklist of a running node, to be wired up alongside this node's
+// other operator-facing commands.
+func BlacklistCommand(blacklist *Blacklist) cli.Command {
+	return cli.Command{
+		Name:  "blacklist",
+		Usage: "Inspects and manages the libp2p peer blacklist",
+		Subcommands: []cli.Command{
+			{
+				Name:      "list",
+				Usage:     "Lists currently blacklisted peers",
+				ArgsUsage: "",
+				Action: func(c *cli.Context) error {
+					return listBlacklist(blacklist)
+				},
+			},
+			{
+				Name:      "add",
+				Usage:     "Adds a peer to the blacklist",
+				ArgsUsage: "PEER_ID TTL",
+				Action: func(c *cli.Context) error {
+					return addToBlacklist(blacklist, c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+			{
+				Name:      "remove",
+				Usage:     "Removes a peer from the blacklist",
+				ArgsUsage: "PEER_ID",
+				Action: func(c *cli.Context) error {
+					return removeFromBlacklist(blacklist, c.Args().Get(0))
+				},
+			},
+		},
+	}
+}
+
+func listBlacklist(blacklist *Blacklist) error {
+	blacklist.mutex.Lock()
+	defer blacklist.mutex.Unlock()
+
+	now := time.Now()
+	listed := 0
+	for id, record := range blacklist.entries {
+		if now.After(record.ExpiresAt) {
+			continue
+		}
+		listed++
+		fmt.Printf(
+			"%v\treason: %v\texpires: %v\n",
+			id.Pretty(),
+			record.Reason,
+			record.ExpiresAt.Format(time.RFC3339),
+		)
+	}
+
+	if listed == 0 {
+		fmt.Println("no blacklisted peers")
+	}
+
+	return nil
+}
+
+func addToBlacklist(blacklist *Blacklist, peerIDString, ttlString string) error {
+	if peerIDString == "" {
+		return fmt.Errorf("PEER_ID is required")
+	}
+
+	id, err := peer.IDB58Decode(peerIDString)
+	if err != nil {
+		return fmt.Errorf("could not parse peer id [%v]", err)
+	}
+
+	ttl := 24 * time.Hour
+	if ttlString != "" {
+		parsed, err := time.ParseDuration(ttlString)
+		if err != nil {
+			return fmt.Errorf("could not parse ttl [%v]", err)
+		}
+		ttl = parsed
+	}
+
+	return blacklist.Add(id, ReasonManual, ttl)
+}
+
+func removeFromBlacklist(blacklist *Blacklist, peerIDString string) error {
+	if peerIDString == "" {
+		return fmt.Errorf("PEER_ID is required")
+	}
+
+	id, err := peer.IDB58Decode(peerIDString)
+	if err != nil {
+		return fmt.Errorf("could not parse peer id [%v]", err)
+	}
+
+	return blacklist.Remove(id)
+}
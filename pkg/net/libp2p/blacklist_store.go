@@ -0,0 +1,109 @@
+package libp2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// fileBlacklistStore persists BlacklistRecords as a single JSON file on disk,
+// so peers blacklisted before a restart stay blacklisted after one.
+type fileBlacklistStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileBlacklistStore returns a BlacklistStore backed by the JSON file at
+// path. The file is created on first Save if it does not already exist.
+func NewFileBlacklistStore(path string) BlacklistStore {
+	return &fileBlacklistStore{path: path}
+}
+
+func (s *fileBlacklistStore) Save(id peer.ID, record BlacklistRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	records[id.Pretty()] = record
+	return s.writeAll(records)
+}
+
+func (s *fileBlacklistStore) Delete(id peer.ID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	delete(records, id.Pretty())
+	return s.writeAll(records)
+}
+
+func (s *fileBlacklistStore) LoadAll() (map[peer.ID]BlacklistRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[peer.ID]BlacklistRecord, len(records))
+	for idString, record := range records {
+		id, err := peer.IDB58Decode(idString)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not decode blacklisted peer id [%v] [%v]",
+				idString,
+				err,
+			)
+		}
+		entries[id] = record
+	}
+
+	return entries, nil
+}
+
+// readAll returns the on-disk records, keyed by the base58-encoded peer.ID,
+// treating a missing file as an empty blacklist.
+func (s *fileBlacklistStore) readAll() (map[string]BlacklistRecord, error) {
+	bytes, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return make(map[string]BlacklistRecord), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read blacklist file [%v]", err)
+	}
+
+	records := make(map[string]BlacklistRecord)
+	if len(bytes) > 0 {
+		if err := json.Unmarshal(bytes, &records); err != nil {
+			return nil, fmt.Errorf("could not parse blacklist file [%v]", err)
+		}
+	}
+
+	return records, nil
+}
+
+func (s *fileBlacklistStore) writeAll(records map[string]BlacklistRecord) error {
+	bytes, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not serialize blacklist [%v]", err)
+	}
+
+	if err := ioutil.WriteFile(s.path, bytes, 0600); err != nil {
+		return fmt.Errorf("could not write blacklist file [%v]", err)
+	}
+
+	return nil
+}
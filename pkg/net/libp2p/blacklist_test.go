@@ -0,0 +1,156 @@
+package libp2p
+
+import (
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// memoryBlacklistStore is a BlacklistStore that never touches disk, for tests
+// that only care about Blacklist's in-memory behavior.
+type memoryBlacklistStore struct {
+	records map[peer.ID]BlacklistRecord
+}
+
+func newMemoryBlacklistStore() *memoryBlacklistStore {
+	return &memoryBlacklistStore{records: make(map[peer.ID]BlacklistRecord)}
+}
+
+func (s *memoryBlacklistStore) Save(id peer.ID, record BlacklistRecord) error {
+	s.records[id] = record
+	return nil
+}
+
+func (s *memoryBlacklistStore) Delete(id peer.ID) error {
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryBlacklistStore) LoadAll() (map[peer.ID]BlacklistRecord, error) {
+	loaded := make(map[peer.ID]BlacklistRecord, len(s.records))
+	for id, record := range s.records {
+		loaded[id] = record
+	}
+	return loaded, nil
+}
+
+func TestBlacklistAddContainsRemove(t *testing.T) {
+	blacklist, err := NewBlacklist(newMemoryBlacklistStore())
+	if err != nil {
+		t.Fatalf("could not create blacklist: %v", err)
+	}
+
+	peerID := peer.ID("test-peer")
+
+	if blacklist.Contains(peerID) {
+		t.Fatal("expected peer not to be blacklisted yet")
+	}
+
+	if err := blacklist.Add(peerID, ReasonManual, time.Hour); err != nil {
+		t.Fatalf("could not add peer to blacklist: %v", err)
+	}
+
+	if !blacklist.Contains(peerID) {
+		t.Fatal("expected peer to be blacklisted")
+	}
+
+	if err := blacklist.Remove(peerID); err != nil {
+		t.Fatalf("could not remove peer from blacklist: %v", err)
+	}
+
+	if blacklist.Contains(peerID) {
+		t.Fatal("expected peer to no longer be blacklisted after Remove")
+	}
+}
+
+func TestBlacklistEntryExpires(t *testing.T) {
+	blacklist, err := NewBlacklist(newMemoryBlacklistStore())
+	if err != nil {
+		t.Fatalf("could not create blacklist: %v", err)
+	}
+
+	peerID := peer.ID("expiring-peer")
+
+	if err := blacklist.Add(peerID, ReasonManual, time.Millisecond); err != nil {
+		t.Fatalf("could not add peer to blacklist: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if blacklist.Contains(peerID) {
+		t.Fatal("expected expired blacklist entry to no longer apply")
+	}
+}
+
+func TestRecordHandshakeFailureBlacklistsAfterThreshold(t *testing.T) {
+	blacklist, err := NewBlacklist(newMemoryBlacklistStore())
+	if err != nil {
+		t.Fatalf("could not create blacklist: %v", err)
+	}
+
+	peerID := peer.ID("flaky-peer")
+
+	for i := 0; i < failuresBeforeBlacklist-1; i++ {
+		blacklist.RecordHandshakeFailure(peerID, ReasonHandshakeVerificationFailed)
+	}
+	if blacklist.Contains(peerID) {
+		t.Fatal("expected peer not to be blacklisted before reaching the failure threshold")
+	}
+
+	blacklist.RecordHandshakeFailure(peerID, ReasonHandshakeVerificationFailed)
+	if !blacklist.Contains(peerID) {
+		t.Fatal("expected peer to be blacklisted after reaching the failure threshold")
+	}
+}
+
+func TestRecordHandshakeFailureResetsOutsideSlidingWindow(t *testing.T) {
+	blacklist, err := NewBlacklist(newMemoryBlacklistStore())
+	if err != nil {
+		t.Fatalf("could not create blacklist: %v", err)
+	}
+
+	peerID := peer.ID("slow-offender")
+
+	// Simulate failuresBeforeBlacklist-1 failures that happened long enough
+	// ago to fall outside failureWindow.
+	blacklist.mutex.Lock()
+	blacklist.failures[peerID] = &failureTracker{
+		count:       failuresBeforeBlacklist - 1,
+		windowStart: time.Now().Add(-2 * failureWindow),
+	}
+	blacklist.mutex.Unlock()
+
+	blacklist.RecordHandshakeFailure(peerID, ReasonHandshakeVerificationFailed)
+
+	if blacklist.Contains(peerID) {
+		t.Fatal("expected a failure outside the sliding window to start a fresh count, not blacklist immediately")
+	}
+}
+
+func TestBlacklistSubscribeReceivesEvents(t *testing.T) {
+	blacklist, err := NewBlacklist(newMemoryBlacklistStore())
+	if err != nil {
+		t.Fatalf("could not create blacklist: %v", err)
+	}
+
+	events := make(chan BlacklistEvent, 2)
+	unsubscribe := blacklist.Subscribe(func(event BlacklistEvent) {
+		events <- event
+	})
+	defer unsubscribe()
+
+	peerID := peer.ID("watched-peer")
+	if err := blacklist.Add(peerID, ReasonManual, time.Hour); err != nil {
+		t.Fatalf("could not add peer to blacklist: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.PeerID != peerID || !event.Added {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for blacklist add event")
+	}
+}
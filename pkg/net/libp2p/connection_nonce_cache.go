@@ -0,0 +1,56 @@
+package libp2p
+
+import (
+	"sync"
+	"time"
+)
+
+// connectionNonceTTL bounds how long a responder remembers an Act1
+// connection nonce it has already seen. It's chosen comfortably longer than
+// any handshake should take, so an expired entry can never be a legitimate
+// connection still in flight.
+const connectionNonceTTL = 10 * time.Minute
+
+// connectionNonceCache remembers the Act1 connection nonces a responder has
+// already seen. The nonce itself is entirely initiator-supplied, so it
+// cannot prove freshness on its own: a captured Act1 envelope replayed
+// verbatim onto a brand-new connection reproduces the same transcript hash
+// and would otherwise pass verification. Rejecting a nonce the responder has
+// already observed closes that gap without requiring the responder to
+// contribute its own challenge into Act1.
+type connectionNonceCache struct {
+	mutex sync.Mutex
+	seen  map[string]time.Time
+}
+
+func newConnectionNonceCache() *connectionNonceCache {
+	return &connectionNonceCache{seen: make(map[string]time.Time)}
+}
+
+// Observe records nonce as seen and reports whether it had already been
+// observed, and not yet expired, before this call.
+func (c *connectionNonceCache) Observe(nonce []byte) bool {
+	key := string(nonce)
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for existingKey, seenAt := range c.seen {
+		if now.Sub(seenAt) > connectionNonceTTL {
+			delete(c.seen, existingKey)
+		}
+	}
+
+	if seenAt, exists := c.seen[key]; exists && now.Sub(seenAt) <= connectionNonceTTL {
+		return true
+	}
+
+	c.seen[key] = now
+	return false
+}
+
+// sharedConnectionNonceCache tracks Act1 connection nonces across every
+// connection this process accepts, since replay must be detected across
+// separate connection attempts rather than within the state of just one.
+var sharedConnectionNonceCache = newConnectionNonceCache()
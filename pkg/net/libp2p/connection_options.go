@@ -0,0 +1,29 @@
+package libp2p
+
+// ConnectionOption configures the behavior of an authenticatedConnection
+// beyond the mutual authentication newAuthenticatedInboundConnection and
+// newAuthenticatedOutboundConnection always provide.
+type ConnectionOption func(*authenticatedConnection)
+
+// WithEncryption opts an authenticatedConnection into confidentiality: once
+// the handshake completes, the connection is wrapped so that all subsequent
+// traffic is encrypted and authenticated with the ChaCha20-Poly1305 session
+// keys derived from both sides' ephemeral Diffie-Hellman keys. Networks that
+// don't pass this option get an authenticated, but still plain-text,
+// connection, as before.
+func WithEncryption() ConnectionOption {
+	return func(ac *authenticatedConnection) {
+		ac.encryptionEnabled = true
+	}
+}
+
+// WithBlacklist rejects connections from peers present in blacklist: an
+// outbound connection before the handshake runs, and an inbound one as soon
+// as the initiator's Act1 envelope names it, before that envelope is verified
+// or answered. It also feeds handshake verification and unmarshal
+// failures back into blacklist so repeat offenders get blocked automatically.
+func WithBlacklist(blacklist *Blacklist) ConnectionOption {
+	return func(ac *authenticatedConnection) {
+		ac.blacklist = blacklist
+	}
+}
@@ -0,0 +1,148 @@
+package libp2p
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/keep-network/keep-core/pkg/net/security/handshake"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// maxEncryptedFrameSize bounds how many plaintext bytes go into a single
+// encrypted frame, so that Write never has to buffer an unbounded amount of
+// ciphertext before handing it to the underlying connection.
+const maxEncryptedFrameSize = 16 * 1024
+
+// encryptedConn wraps an already-authenticated net.Conn and transparently
+// encrypts and authenticates everything that crosses it, using the
+// directional ChaCha20-Poly1305 keys derived at the end of the handshake. It
+// is only installed when the connection was built with WithEncryption.
+type encryptedConn struct {
+	net.Conn
+
+	encrypt cipher.AEAD
+	decrypt cipher.AEAD
+
+	writeNonce uint64
+	readNonce  uint64
+
+	// readBuf holds plaintext left over from a frame whose content was
+	// larger than the caller's Read buffer.
+	readBuf []byte
+}
+
+// newEncryptedConn builds an encryptedConn around conn using the session keys
+// derived during the handshake.
+func newEncryptedConn(conn net.Conn, keys *handshake.SessionKeys) (*encryptedConn, error) {
+	encrypt, err := chacha20poly1305.New(keys.EncryptKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not build encrypt cipher [%v]", err)
+	}
+
+	decrypt, err := chacha20poly1305.New(keys.DecryptKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not build decrypt cipher [%v]", err)
+	}
+
+	return &encryptedConn{Conn: conn, encrypt: encrypt, decrypt: decrypt}, nil
+}
+
+// Write splits p into frames of at most maxEncryptedFrameSize plaintext
+// bytes, seals each with the next nonce in the write direction, and writes
+// out a 2-byte big-endian plaintext length prefix followed by the sealed
+// frame. The length prefix is authenticated as associated data, so it can't
+// be tampered with independently of the ciphertext it describes.
+func (ec *encryptedConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxEncryptedFrameSize {
+			chunk = chunk[:maxEncryptedFrameSize]
+		}
+
+		lengthAD := make([]byte, 2)
+		binary.BigEndian.PutUint16(lengthAD, uint16(len(chunk)))
+
+		sealed := ec.encrypt.Seal(nil, ec.nextWriteNonce(), chunk, lengthAD)
+
+		if _, err := ec.Conn.Write(append(lengthAD, sealed...)); err != nil {
+			return written, err
+		}
+
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// Read fills p from any buffered plaintext first, then reads and decrypts
+// frames from the underlying connection as needed. It returns an error if a
+// frame's authentication tag fails to verify.
+//
+// Any error from Read means the read-direction nonce counter and the peer's
+// write-direction counter may now disagree on how many frames have been
+// consumed - for a length/IO error, because no frame was decrypted at all;
+// for an authentication failure, because the counter is deliberately left
+// unadvanced (see readFrame). Either way, the connection can no longer be
+// trusted to resynchronize and must be torn down rather than read from again.
+func (ec *encryptedConn) Read(p []byte) (int, error) {
+	if len(ec.readBuf) == 0 {
+		frame, err := ec.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		ec.readBuf = frame
+	}
+
+	n := copy(p, ec.readBuf)
+	ec.readBuf = ec.readBuf[n:]
+	return n, nil
+}
+
+func (ec *encryptedConn) readFrame() ([]byte, error) {
+	lengthAD := make([]byte, 2)
+	if _, err := io.ReadFull(ec.Conn, lengthAD); err != nil {
+		return nil, err
+	}
+	frameLength := int(binary.BigEndian.Uint16(lengthAD))
+
+	sealed := make([]byte, frameLength+ec.decrypt.Overhead())
+	if _, err := io.ReadFull(ec.Conn, sealed); err != nil {
+		return nil, err
+	}
+
+	// ec.readNonce only advances once Open has confirmed this frame is
+	// authentic. Advancing it unconditionally would mean a single injected
+	// frame, one the sender never sent and so never spent a nonce on,
+	// permanently desynchronizes the counter from the sender's, breaking
+	// decryption of every legitimate frame that follows. A frame the sender
+	// did send but that was corrupted in transit leaves the counters apart
+	// either way (see Read).
+	nonce := nonceFromCounter(ec.readNonce)
+	plaintext, err := ec.decrypt.Open(nil, nonce, sealed, lengthAD)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted frame authentication failed [%v]", err)
+	}
+	ec.readNonce++
+
+	return plaintext, nil
+}
+
+// nextWriteNonce hands out monotonically incrementing 96-bit nonces for the
+// write direction. The read direction's counter is advanced directly in
+// readFrame, only once a frame has actually authenticated.
+func (ec *encryptedConn) nextWriteNonce() []byte {
+	nonce := nonceFromCounter(ec.writeNonce)
+	ec.writeNonce++
+	return nonce
+}
+
+func nonceFromCounter(counter uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], counter)
+	return nonce
+}
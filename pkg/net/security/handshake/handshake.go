@@ -0,0 +1,237 @@
+// Package handshake implements the three-act handshake pkg/net/libp2p uses to
+// upgrade a freshly-dialed or freshly-accepted connection into one where both
+// ends have proven possession of the private key behind their libp2p
+// peer.ID.
+//
+// Act1 and Act2 additionally carry each side's ephemeral X25519 public key.
+// Once Act2 has been processed by the initiator (and Act3 by the responder),
+// both sides can call SessionKeys to obtain a pair of directional AEAD keys
+// derived from the ECDH shared secret, for use by an optional encrypted
+// transport; a handshake that never calls SessionKeys is unaffected by this.
+package handshake
+
+import "fmt"
+
+// Act1Message is the payload of the handshake's first message, sent by the
+// initiator to the responder. Authentication of the sender happens one layer
+// up, where this message is wrapped in a signed envelope.
+type Act1Message struct {
+	ephemeralPublicKey [32]byte
+}
+
+// Marshal converts this Act1Message to a byte array.
+func (m *Act1Message) Marshal() ([]byte, error) {
+	wire := make([]byte, 32)
+	copy(wire, m.ephemeralPublicKey[:])
+	return wire, nil
+}
+
+// Unmarshal converts a byte array to this Act1Message.
+func (m *Act1Message) Unmarshal(bytes []byte) error {
+	if len(bytes) != 32 {
+		return fmt.Errorf(
+			"act1 message expected 32 bytes, got %d",
+			len(bytes),
+		)
+	}
+	copy(m.ephemeralPublicKey[:], bytes)
+	return nil
+}
+
+// Act2Message is the payload of the handshake's second message, sent by the
+// responder to the initiator in answer to Act1.
+type Act2Message struct {
+	ephemeralPublicKey [32]byte
+}
+
+// Marshal converts this Act2Message to a byte array.
+func (m *Act2Message) Marshal() ([]byte, error) {
+	wire := make([]byte, 32)
+	copy(wire, m.ephemeralPublicKey[:])
+	return wire, nil
+}
+
+// Unmarshal converts a byte array to this Act2Message.
+func (m *Act2Message) Unmarshal(bytes []byte) error {
+	if len(bytes) != 32 {
+		return fmt.Errorf(
+			"act2 message expected 32 bytes, got %d",
+			len(bytes),
+		)
+	}
+	copy(m.ephemeralPublicKey[:], bytes)
+	return nil
+}
+
+// Act3Message is the payload of the handshake's third and final message,
+// sent by the initiator to the responder to complete the handshake.
+type Act3Message struct{}
+
+// Marshal converts this Act3Message to a byte array.
+func (m *Act3Message) Marshal() ([]byte, error) {
+	return []byte{}, nil
+}
+
+// Unmarshal converts a byte array to this Act3Message.
+func (m *Act3Message) Unmarshal(bytes []byte) error {
+	if len(bytes) != 0 {
+		return fmt.Errorf(
+			"act3 message expected 0 bytes, got %d",
+			len(bytes),
+		)
+	}
+	return nil
+}
+
+// InitiatorAct1 is the initiator's state after generating its ephemeral key
+// pair but before it has heard back from the responder.
+type InitiatorAct1 struct {
+	ephemeralPrivateKey [32]byte
+	ephemeralPublicKey  [32]byte
+}
+
+// InitiateHandshake generates the initiator's ephemeral Diffie-Hellman key
+// pair and returns the initiator's Act1 station.
+func InitiateHandshake() (*InitiatorAct1, error) {
+	ephemeralPrivateKey, ephemeralPublicKey, err := generateEphemeralKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not generate initiator ephemeral key pair [%v]",
+			err,
+		)
+	}
+
+	return &InitiatorAct1{
+		ephemeralPrivateKey: ephemeralPrivateKey,
+		ephemeralPublicKey:  ephemeralPublicKey,
+	}, nil
+}
+
+// Message returns the wire-level Act1Message this station sends to the
+// responder.
+func (ia1 *InitiatorAct1) Message() *Act1Message {
+	return &Act1Message{ephemeralPublicKey: ia1.ephemeralPublicKey}
+}
+
+// Next advances the initiator to its Act2 station.
+func (ia1 *InitiatorAct1) Next() *InitiatorAct2 {
+	return &InitiatorAct2{
+		ephemeralPrivateKey: ia1.ephemeralPrivateKey,
+		ephemeralPublicKey:  ia1.ephemeralPublicKey,
+	}
+}
+
+// InitiatorAct2 is the initiator's state while it awaits the responder's
+// Act2Message.
+type InitiatorAct2 struct {
+	ephemeralPrivateKey [32]byte
+	ephemeralPublicKey  [32]byte
+}
+
+// Next processes the responder's Act2Message, derives the shared session
+// keys, and returns the initiator's Act3 station.
+func (ia2 *InitiatorAct2) Next(message *Act2Message) (*InitiatorAct3, error) {
+	sessionKeys, err := deriveSessionKeys(
+		ia2.ephemeralPrivateKey,
+		ia2.ephemeralPublicKey,
+		message.ephemeralPublicKey,
+		initiatorRole,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive session keys [%v]", err)
+	}
+
+	return &InitiatorAct3{sessionKeys: sessionKeys}, nil
+}
+
+// InitiatorAct3 is the initiator's final state, reached once session keys
+// have been derived and the handshake is ready to be completed.
+type InitiatorAct3 struct {
+	sessionKeys *SessionKeys
+}
+
+// Message returns the wire-level Act3Message this station sends to the
+// responder to complete the handshake.
+func (ia3 *InitiatorAct3) Message() *Act3Message {
+	return &Act3Message{}
+}
+
+// SessionKeys returns the directional AEAD keys derived during this
+// handshake, for use by an optional encrypted transport.
+func (ia3 *InitiatorAct3) SessionKeys() *SessionKeys {
+	return ia3.sessionKeys
+}
+
+// ResponderAct2 is the responder's state after receiving Act1 and generating
+// its own ephemeral key pair in answer.
+type ResponderAct2 struct {
+	remoteEphemeralPublicKey [32]byte
+	ephemeralPrivateKey      [32]byte
+	ephemeralPublicKey       [32]byte
+}
+
+// AnswerHandshake processes the initiator's Act1Message, generates the
+// responder's ephemeral Diffie-Hellman key pair, and returns the responder's
+// Act2 station.
+func AnswerHandshake(message *Act1Message) (*ResponderAct2, error) {
+	ephemeralPrivateKey, ephemeralPublicKey, err := generateEphemeralKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not generate responder ephemeral key pair [%v]",
+			err,
+		)
+	}
+
+	return &ResponderAct2{
+		remoteEphemeralPublicKey: message.ephemeralPublicKey,
+		ephemeralPrivateKey:      ephemeralPrivateKey,
+		ephemeralPublicKey:       ephemeralPublicKey,
+	}, nil
+}
+
+// Message returns the wire-level Act2Message this station sends to the
+// initiator.
+func (ra2 *ResponderAct2) Message() *Act2Message {
+	return &Act2Message{ephemeralPublicKey: ra2.ephemeralPublicKey}
+}
+
+// Next advances the responder to its Act3 station.
+func (ra2 *ResponderAct2) Next() *ResponderAct3 {
+	return &ResponderAct3{
+		remoteEphemeralPublicKey: ra2.remoteEphemeralPublicKey,
+		ephemeralPrivateKey:      ra2.ephemeralPrivateKey,
+		ephemeralPublicKey:       ra2.ephemeralPublicKey,
+	}
+}
+
+// ResponderAct3 is the responder's state while it awaits the initiator's
+// Act3Message to finalize the handshake.
+type ResponderAct3 struct {
+	remoteEphemeralPublicKey [32]byte
+	ephemeralPrivateKey      [32]byte
+	ephemeralPublicKey       [32]byte
+	sessionKeys              *SessionKeys
+}
+
+// FinalizeHandshake processes the initiator's Act3Message and derives the
+// shared session keys, completing the handshake.
+func (ra3 *ResponderAct3) FinalizeHandshake(message *Act3Message) error {
+	sessionKeys, err := deriveSessionKeys(
+		ra3.ephemeralPrivateKey,
+		ra3.ephemeralPublicKey,
+		ra3.remoteEphemeralPublicKey,
+		responderRole,
+	)
+	if err != nil {
+		return fmt.Errorf("could not derive session keys [%v]", err)
+	}
+
+	ra3.sessionKeys = sessionKeys
+	return nil
+}
+
+// SessionKeys returns the directional AEAD keys derived during this
+// handshake, for use by an optional encrypted transport.
+func (ra3 *ResponderAct3) SessionKeys() *SessionKeys {
+	return ra3.sessionKeys
+}
@@ -0,0 +1,80 @@
+package handshake
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SessionKeys holds the pair of directional AEAD keys derived from a
+// completed handshake. EncryptKey authenticates and encrypts traffic sent by
+// this side; DecryptKey authenticates and decrypts traffic received from the
+// other side.
+type SessionKeys struct {
+	EncryptKey [32]byte
+	DecryptKey [32]byte
+}
+
+// role distinguishes the two sides of a handshake for the purposes of
+// deriving session keys and the transcript they're bound to.
+type role int
+
+const (
+	initiatorRole role = iota
+	responderRole
+)
+
+// generateEphemeralKeyPair creates a fresh X25519 key pair to be used for a
+// single handshake and discarded afterwards.
+func generateEphemeralKeyPair() (privateKey, publicKey [32]byte, err error) {
+	if _, err = io.ReadFull(rand.Reader, privateKey[:]); err != nil {
+		return privateKey, publicKey, err
+	}
+
+	curve25519.ScalarBaseMult(&publicKey, &privateKey)
+	return privateKey, publicKey, nil
+}
+
+// deriveSessionKeys runs X25519 ECDH between the local ephemeral private key
+// and the remote ephemeral public key, then stretches the resulting shared
+// secret with HKDF-SHA256, with the transcript of both sides' ephemeral
+// public keys in initiator-then-responder order as HKDF's info input, into a
+// pair of directional
+// AEAD keys. Both sides derive the same two keys, one per direction, so what
+// the initiator encrypts with is what the responder decrypts with and vice
+// versa.
+func deriveSessionKeys(
+	localPrivateKey, localPublicKey, remotePublicKey [32]byte,
+	localRole role,
+) (*SessionKeys, error) {
+	sharedSecret, err := curve25519.X25519(localPrivateKey[:], remotePublicKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var initiatorPublicKey, responderPublicKey [32]byte
+	if localRole == initiatorRole {
+		initiatorPublicKey, responderPublicKey = localPublicKey, remotePublicKey
+	} else {
+		initiatorPublicKey, responderPublicKey = remotePublicKey, localPublicKey
+	}
+	transcript := append(append([]byte{}, initiatorPublicKey[:]...), responderPublicKey[:]...)
+
+	kdf := hkdf.New(sha256.New, sharedSecret, nil, transcript)
+
+	var initiatorKey, responderKey [32]byte
+	if _, err := io.ReadFull(kdf, initiatorKey[:]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(kdf, responderKey[:]); err != nil {
+		return nil, err
+	}
+
+	if localRole == initiatorRole {
+		return &SessionKeys{EncryptKey: initiatorKey, DecryptKey: responderKey}, nil
+	}
+	return &SessionKeys{EncryptKey: responderKey, DecryptKey: initiatorKey}, nil
+}